@@ -0,0 +1,145 @@
+/*
+* apply.go
+* GoHue library for Philips Hue
+* Copyright (C) 2016 Collin Guarino (Collinux) collin.guarino@gmail.com
+* License: GPL version 2 or higher http://www.gnu.org/licenses/gpl.html
+*/
+// http://www.developers.meethue.com/documentation/lights-api
+
+package hue
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "sync"
+
+    "golang.org/x/sync/errgroup"
+)
+
+// DefaultApplyConcurrency caps the number of simultaneous PUTs issued by
+// Bridge.ApplyLightStates when the caller hasn't overridden it. The bridge
+// rate-limits commands sent to lights to roughly 10/sec, so this is kept
+// conservatively low.
+var DefaultApplyConcurrency = 4
+
+// ApplyError aggregates the per-light failures from Bridge.ApplyLightStates
+// so a caller can see which bulbs failed without the whole batch aborting.
+type ApplyError struct {
+    Errors map[int]error
+}
+
+// Error satisfies the error interface, listing each failed light index
+// in ascending order along with its underlying error.
+func (e *ApplyError) Error() string {
+    indices := make([]int, 0, len(e.Errors))
+    for index := range e.Errors {
+        indices = append(indices, index)
+    }
+    sort.Ints(indices)
+
+    msg := fmt.Sprintf("hue: ApplyLightStates failed for %d light(s):", len(indices))
+    for _, index := range indices {
+        msg += fmt.Sprintf(" %d (%v)", index, e.Errors[index])
+    }
+    return msg
+}
+
+// Bridge.ApplyLightStates fans the given per-light `updates` out as
+// concurrent PUTs instead of a serial loop, bounded by DefaultApplyConcurrency
+// simultaneous requests to stay under the bridge's rate limit for lights.
+// Each update is routed through applyQuirks and the state cache exactly
+// like Light.SetState/SetStateContext, so a TradFri bulb in a batch still
+// gets its split PUT and a cached, already-applied state is still skipped.
+// Once every PUT has settled, the post-apply refresh is coalesced into a
+// single `GetAllLights` call rather than one GET per light. A per-light PUT
+// failure does not abort the others; failures are collected and returned
+// together as an `*ApplyError`. Cancelling `ctx` stops outstanding PUTs as
+// soon as possible.
+func (bridge *Bridge) ApplyLightStates(ctx context.Context, updates map[int]LightState) error {
+    cache := cacheFor(bridge)
+    group, ctx := errgroup.WithContext(ctx)
+    sem := make(chan struct{}, DefaultApplyConcurrency)
+
+    var mutex sync.Mutex
+    errs := make(map[int]error)
+
+    for index, state := range updates {
+        index, state := index, state
+        group.Go(func() error {
+            if cache.checkCache(index, state) {
+                return nil
+            }
+
+            select {
+            case sem <- struct{}{}:
+            case <-ctx.Done():
+                return ctx.Err()
+            }
+            defer func() { <-sem }()
+
+            light, err := bridge.GetLightByIndex(index)
+            if err != nil {
+                mutex.Lock()
+                errs[index] = err
+                mutex.Unlock()
+                return nil
+            }
+
+            uri := fmt.Sprintf("/api/%s/lights/%d/state", bridge.Username, index)
+            first, second := applyQuirks(light, state)
+            if _, _, err := bridge.PutContext(ctx, uri, first); err != nil {
+                mutex.Lock()
+                errs[index] = err
+                mutex.Unlock()
+                return nil
+            }
+            if second != nil {
+                if _, _, err := bridge.PutContext(ctx, uri, *second); err != nil {
+                    mutex.Lock()
+                    errs[index] = err
+                    mutex.Unlock()
+                }
+            }
+            return nil
+        })
+    }
+
+    if err := group.Wait(); err != nil {
+        return err
+    }
+
+    lights, err := bridge.GetAllLights()
+    if err != nil {
+        return err
+    }
+    for _, light := range lights {
+        state, ok := updates[light.Index]
+        if !ok {
+            continue
+        }
+        if _, failed := errs[light.Index]; failed {
+            continue
+        }
+        if light.State.Reachable {
+            cache.record(light.Index, state, true)
+        } else {
+            bridge.InvalidateLightCache(light.Index)
+        }
+    }
+
+    if len(errs) > 0 {
+        return &ApplyError{Errors: errs}
+    }
+    return nil
+}
+
+// Group.Apply applies a single LightState to every light in the group by
+// fanning the update out through Bridge.ApplyLightStates.
+func (group *Group) Apply(ctx context.Context, state LightState) error {
+    updates := make(map[int]LightState, len(group.LightIndices))
+    for _, index := range group.LightIndices {
+        updates[index] = state
+    }
+    return group.Bridge.ApplyLightStates(ctx, updates)
+}
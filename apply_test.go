@@ -0,0 +1,180 @@
+/*
+* apply_test.go
+* GoHue library for Philips Hue
+* Copyright (C) 2016 Collin Guarino (Collinux) collin.guarino@gmail.com
+* License: GPL version 2 or higher http://www.gnu.org/licenses/gpl.html
+*/
+
+package hue
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// newTestBridge starts an httptest server backing a Bridge's lights API and
+// returns both so callers can point handlers at individual endpoints.
+func newTestBridge(t *testing.T, mux *http.ServeMux) (*Bridge, *httptest.Server) {
+    t.Helper()
+    server := httptest.NewServer(mux)
+    t.Cleanup(server.Close)
+
+    bridge := &Bridge{
+        IPAddress: strings.TrimPrefix(server.URL, "http://"),
+        Username:  "testuser",
+    }
+    return bridge, server
+}
+
+func lightsHandler(count int) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        lights := make(map[string]Light)
+        for i := 0; i < count; i++ {
+            var light Light
+            light.State.Reachable = true
+            lights[fmt.Sprintf("%d", i)] = light
+        }
+        json.NewEncoder(w).Encode(lights)
+    }
+}
+
+func lightByIndexHandler() http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        var light Light
+        light.State.Reachable = true
+        json.NewEncoder(w).Encode(light)
+    }
+}
+
+func TestApplyLightStatesRespectsConcurrencyCap(t *testing.T) {
+    var inFlight int32
+    var maxInFlight int32
+    var mutex sync.Mutex
+
+    mux := http.NewServeMux()
+    mux.HandleFunc("/api/testuser/lights/", func(w http.ResponseWriter, r *http.Request) {
+        if r.Method == http.MethodPut {
+            current := atomic.AddInt32(&inFlight, 1)
+            mutex.Lock()
+            if current > maxInFlight {
+                maxInFlight = current
+            }
+            mutex.Unlock()
+            time.Sleep(20 * time.Millisecond)
+            atomic.AddInt32(&inFlight, -1)
+            w.Write([]byte(`[{"success":{}}]`))
+            return
+        }
+        lightByIndexHandler()(w, r)
+    })
+    mux.HandleFunc("/api/testuser/lights", lightsHandler(20))
+
+    bridge, _ := newTestBridge(t, mux)
+
+    updates := make(map[int]LightState, 20)
+    for i := 0; i < 20; i++ {
+        updates[i] = LightState{On: true, Bri: uint8(100 + i)}
+    }
+
+    if err := bridge.ApplyLightStates(context.Background(), updates); err != nil {
+        t.Fatalf("ApplyLightStates returned an error: %v", err)
+    }
+
+    if maxInFlight > int32(DefaultApplyConcurrency) {
+        t.Errorf("observed %d concurrent PUTs, want at most %d", maxInFlight, DefaultApplyConcurrency)
+    }
+}
+
+func TestApplyLightStatesAggregatesPerLightErrors(t *testing.T) {
+    const failingIndex = 2
+
+    mux := http.NewServeMux()
+    mux.HandleFunc("/api/testuser/lights/", func(w http.ResponseWriter, r *http.Request) {
+        if r.Method == http.MethodPut {
+            if strings.Contains(r.URL.Path, fmt.Sprintf("/lights/%d/", failingIndex)) {
+                // Hijack and close without writing a response so the
+                // client sees a genuine request error, since Put/PutContext
+                // don't treat a non-2xx status as a failure.
+                hijacker, ok := w.(http.Hijacker)
+                if !ok {
+                    t.Fatalf("ResponseWriter does not support hijacking")
+                }
+                conn, _, err := hijacker.Hijack()
+                if err != nil {
+                    t.Fatalf("hijack failed: %v", err)
+                }
+                conn.Close()
+                return
+            }
+            w.Write([]byte(`[{"success":{}}]`))
+            return
+        }
+        lightByIndexHandler()(w, r)
+    })
+    mux.HandleFunc("/api/testuser/lights", lightsHandler(5))
+
+    bridge, _ := newTestBridge(t, mux)
+
+    updates := make(map[int]LightState, 5)
+    for i := 0; i < 5; i++ {
+        updates[i] = LightState{On: true}
+    }
+
+    err := bridge.ApplyLightStates(context.Background(), updates)
+    if err == nil {
+        t.Fatalf("expected an error from the failing light, got nil")
+    }
+
+    applyErr, ok := err.(*ApplyError)
+    if !ok {
+        t.Fatalf("expected *ApplyError, got %T: %v", err, err)
+    }
+    if len(applyErr.Errors) != 1 {
+        t.Fatalf("expected exactly 1 failed light, got %d: %v", len(applyErr.Errors), applyErr.Errors)
+    }
+    if _, ok := applyErr.Errors[failingIndex]; !ok {
+        t.Errorf("expected light %d to be reported as failed, got %v", failingIndex, applyErr.Errors)
+    }
+}
+
+func TestApplyLightStatesCancelContextShortCircuits(t *testing.T) {
+    mux := http.NewServeMux()
+    mux.HandleFunc("/api/testuser/lights/", func(w http.ResponseWriter, r *http.Request) {
+        if r.Method == http.MethodPut {
+            time.Sleep(2 * time.Second)
+            w.Write([]byte(`[{"success":{}}]`))
+            return
+        }
+        lightByIndexHandler()(w, r)
+    })
+    mux.HandleFunc("/api/testuser/lights", lightsHandler(20))
+
+    bridge, _ := newTestBridge(t, mux)
+
+    updates := make(map[int]LightState, 20)
+    for i := 0; i < 20; i++ {
+        updates[i] = LightState{On: true}
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+    defer cancel()
+
+    start := time.Now()
+    err := bridge.ApplyLightStates(ctx, updates)
+    elapsed := time.Since(start)
+
+    if err == nil {
+        t.Fatalf("expected a context error, got nil")
+    }
+    if elapsed > time.Second {
+        t.Errorf("ApplyLightStates took %v to return after ctx expired, want well under the 2s PUT delay", elapsed)
+    }
+}
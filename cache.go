@@ -0,0 +1,145 @@
+/*
+* cache.go
+* GoHue library for Philips Hue
+* Copyright (C) 2016 Collin Guarino (Collinux) collin.guarino@gmail.com
+* License: GPL version 2 or higher http://www.gnu.org/licenses/gpl.html
+*/
+// http://www.developers.meethue.com/documentation/lights-api
+
+package hue
+
+import "sync"
+
+// xyTolerance is how close two xy floats must be to be considered the
+// same color when comparing against the state cache.
+const xyTolerance = 0.0005
+
+// lightStateCache remembers the last LightState written to each light on
+// a bridge, so Light.SetState can skip redundant PUTs.
+type lightStateCache struct {
+    mutex   sync.Mutex
+    enabled bool
+    states  map[int]LightState
+}
+
+var stateCaches = struct {
+    mutex    sync.Mutex
+    byBridge map[*Bridge]*lightStateCache
+}{byBridge: make(map[*Bridge]*lightStateCache)}
+
+// cacheFor returns the state cache for bridge, creating it on first use.
+func cacheFor(bridge *Bridge) *lightStateCache {
+    stateCaches.mutex.Lock()
+    defer stateCaches.mutex.Unlock()
+
+    cache, ok := stateCaches.byBridge[bridge]
+    if !ok {
+        cache = &lightStateCache{states: make(map[int]LightState)}
+        stateCaches.byBridge[bridge] = cache
+    }
+    return cache
+}
+
+// Bridge.EnableStateCache turns the in-memory last-written-state cache on
+// or off. While enabled, Light.SetState skips the PUT entirely when the
+// requested state already matches what was last written to that light
+// (within xyTolerance for xy floats), which matters because the bridge
+// silently drops commands past roughly 10/sec and rapid UI sliders flood
+// it with duplicates. Callers running animation loops that need every
+// frame sent should turn it off. Disabling the cache also clears it.
+func (bridge *Bridge) EnableStateCache(enabled bool) {
+    cache := cacheFor(bridge)
+    cache.mutex.Lock()
+    defer cache.mutex.Unlock()
+
+    cache.enabled = enabled
+    if !enabled {
+        cache.states = make(map[int]LightState)
+    }
+}
+
+// Bridge.InvalidateLightCache forgets the last-written state for the
+// light at index, forcing the next SetState call to PUT regardless of
+// whether it matches.
+func (bridge *Bridge) InvalidateLightCache(index int) {
+    cache := cacheFor(bridge)
+    cache.mutex.Lock()
+    defer cache.mutex.Unlock()
+
+    delete(cache.states, index)
+}
+
+// checkCache reports whether state can be skipped: the cache is enabled
+// and state matches the last state cached for index.
+func (cache *lightStateCache) checkCache(index int, state LightState) (skip bool) {
+    cache.mutex.Lock()
+    defer cache.mutex.Unlock()
+
+    if !cache.enabled {
+        return false
+    }
+    last, ok := cache.states[index]
+    if !ok {
+        return false
+    }
+    return lightStatesEqual(last, state)
+}
+
+// record stores state as the last value written to index, or forgets it
+// entirely when the light reported itself unreachable on refresh.
+func (cache *lightStateCache) record(index int, state LightState, reachable bool) {
+    cache.mutex.Lock()
+    defer cache.mutex.Unlock()
+
+    if !cache.enabled {
+        return
+    }
+    if !reachable {
+        delete(cache.states, index)
+        return
+    }
+    cache.states[index] = state
+}
+
+// lightStatesEqual compares two LightStates for the purposes of the state
+// cache, treating xy coordinates within xyTolerance of each other as equal.
+// Relative adjustments (the `*Increment` fields) and `Name` are never
+// idempotent no-ops, so a state carrying any of them is always treated as
+// not equal and passed through to the bridge.
+func lightStatesEqual(a, b LightState) bool {
+    if hasRelativeFields(a) || hasRelativeFields(b) {
+        return false
+    }
+
+    if a.On != b.On || a.Bri != b.Bri || a.Hue != b.Hue || a.Sat != b.Sat ||
+        a.CT != b.CT || a.Effect != b.Effect || a.Alert != b.Alert ||
+        a.TransitionTime != b.TransitionTime {
+        return false
+    }
+
+    switch {
+    case a.XY == nil && b.XY == nil:
+        return true
+    case a.XY == nil || b.XY == nil:
+        return false
+    default:
+        return floatNear(a.XY[0], b.XY[0]) && floatNear(a.XY[1], b.XY[1])
+    }
+}
+
+// hasRelativeFields reports whether state carries a relative adjustment or
+// a name change, neither of which can be safely deduped against a prior
+// absolute state.
+func hasRelativeFields(state LightState) bool {
+    return state.SaturationIncrement != 0 || state.HueIncrement != 0 ||
+        state.BrightnessIncrement != 0 || state.CTIncrement != 0 ||
+        state.XYIncrement != nil || state.Name != ""
+}
+
+func floatNear(a, b float32) bool {
+    diff := a - b
+    if diff < 0 {
+        diff = -diff
+    }
+    return diff <= xyTolerance
+}
@@ -0,0 +1,84 @@
+/*
+* cache_test.go
+* GoHue library for Philips Hue
+* Copyright (C) 2016 Collin Guarino (Collinux) collin.guarino@gmail.com
+* License: GPL version 2 or higher http://www.gnu.org/licenses/gpl.html
+*/
+
+package hue
+
+import "testing"
+
+func TestLightStatesEqualAbsoluteStates(t *testing.T) {
+    xyA := [2]float32{0.4, 0.4}
+    xyB := [2]float32{0.4002, 0.3998}
+    xyFar := [2]float32{0.5, 0.5}
+
+    cases := []struct {
+        name string
+        a, b LightState
+        want bool
+    }{
+        {"identical", LightState{On: true, Bri: 100}, LightState{On: true, Bri: 100}, true},
+        {"different bri", LightState{On: true, Bri: 100}, LightState{On: true, Bri: 101}, false},
+        {"xy within tolerance", LightState{On: true, XY: &xyA}, LightState{On: true, XY: &xyB}, true},
+        {"xy outside tolerance", LightState{On: true, XY: &xyA}, LightState{On: true, XY: &xyFar}, false},
+        {"one nil xy", LightState{On: true, XY: &xyA}, LightState{On: true}, false},
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            if got := lightStatesEqual(c.a, c.b); got != c.want {
+                t.Errorf("lightStatesEqual(%v, %v) = %v, want %v", c.a, c.b, got, c.want)
+            }
+        })
+    }
+}
+
+func TestLightStatesEqualRelativeAdjustmentsNeverMatch(t *testing.T) {
+    cases := []struct {
+        name string
+        a, b LightState
+    }{
+        {"brightness increment", LightState{On: true, BrightnessIncrement: 5}, LightState{On: true, BrightnessIncrement: 5}},
+        {"hue increment", LightState{On: true, HueIncrement: 100}, LightState{On: true, HueIncrement: 100}},
+        {"saturation increment", LightState{On: true, SaturationIncrement: 5}, LightState{On: true, SaturationIncrement: 5}},
+        {"ct increment", LightState{On: true, CTIncrement: 5}, LightState{On: true, CTIncrement: 5}},
+        {"name change", LightState{Name: "Lamp"}, LightState{Name: "Lamp"}},
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            if lightStatesEqual(c.a, c.b) {
+                t.Errorf("lightStatesEqual(%v, %v) = true, want false (not idempotent)", c.a, c.b)
+            }
+        })
+    }
+}
+
+func TestCheckCacheDoesNotSkipRelativeAdjustments(t *testing.T) {
+    cache := &lightStateCache{enabled: true, states: make(map[int]LightState)}
+    cache.record(1, LightState{On: true, BrightnessIncrement: 5}, true)
+
+    if cache.checkCache(1, LightState{On: true, BrightnessIncrement: 5}) {
+        t.Errorf("checkCache skipped a repeated relative adjustment, but each one must be applied")
+    }
+}
+
+func TestCheckCacheSkipsMatchingAbsoluteState(t *testing.T) {
+    cache := &lightStateCache{enabled: true, states: make(map[int]LightState)}
+    cache.record(1, LightState{On: true, Bri: 100}, true)
+
+    if !cache.checkCache(1, LightState{On: true, Bri: 100}) {
+        t.Errorf("checkCache did not skip an identical, already-applied absolute state")
+    }
+}
+
+func TestCacheRecordForgetsUnreachableLight(t *testing.T) {
+    cache := &lightStateCache{enabled: true, states: make(map[int]LightState)}
+    cache.record(1, LightState{On: true, Bri: 100}, false)
+
+    if cache.checkCache(1, LightState{On: true, Bri: 100}) {
+        t.Errorf("checkCache should not skip a PUT for a light that just went unreachable")
+    }
+}
@@ -0,0 +1,167 @@
+/*
+* context.go
+* GoHue library for Philips Hue
+* Copyright (C) 2016 Collin Guarino (Collinux) collin.guarino@gmail.com
+* License: GPL version 2 or higher http://www.gnu.org/licenses/gpl.html
+*/
+// http://www.developers.meethue.com/documentation/lights-api
+
+package hue
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "time"
+)
+
+// Bridge.PutContext is the context-aware counterpart to Bridge.Put: it
+// issues the same PUT request, but via http.NewRequestWithContext so the
+// caller can cancel or time out the call.
+func (bridge *Bridge) PutContext(ctx context.Context, uri string, body interface{}) ([]byte, int, error) {
+    payload, err := json.Marshal(body)
+    if err != nil {
+        return nil, 0, err
+    }
+
+    url := fmt.Sprintf("http://%s%s", bridge.IPAddress, uri)
+    req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(payload))
+    if err != nil {
+        return nil, 0, err
+    }
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return nil, 0, err
+    }
+    defer resp.Body.Close()
+
+    respBody, err := ioutil.ReadAll(resp.Body)
+    if err != nil {
+        return nil, resp.StatusCode, err
+    }
+    return respBody, resp.StatusCode, nil
+}
+
+// Bridge.DeleteContext is the context-aware counterpart to Bridge.Delete.
+func (bridge *Bridge) DeleteContext(ctx context.Context, uri string) error {
+    url := fmt.Sprintf("http://%s%s", bridge.IPAddress, uri)
+    req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+    if err != nil {
+        return err
+    }
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    return nil
+}
+
+// Light.SetStateContext is the context-aware counterpart to
+// Light.SetState.
+func (light *Light) SetStateContext(ctx context.Context, newState LightState) error {
+    cache := cacheFor(light.Bridge)
+    if cache.checkCache(light.Index, newState) {
+        return nil
+    }
+
+    uri := fmt.Sprintf("/api/%s/lights/%d/state", light.Bridge.Username, light.Index)
+
+    first, second := applyQuirks(*light, newState)
+    _, _, err := light.Bridge.PutContext(ctx, uri, first)
+    if err != nil {
+        return err
+    }
+    if second != nil {
+        _, _, err = light.Bridge.PutContext(ctx, uri, *second)
+        if err != nil {
+            return err
+        }
+    }
+
+    *light, err = light.Bridge.GetLightByIndex(light.Index)
+    if err != nil {
+        return err
+    }
+    cache.record(light.Index, newState, light.State.Reachable)
+    return nil
+}
+
+// Light.SetNameContext is the context-aware counterpart to
+// Light.SetName.
+func (light *Light) SetNameContext(ctx context.Context, name string) error {
+    uri := fmt.Sprintf("/api/%s/lights/%d", light.Bridge.Username, light.Index)
+    body := map[string]string{"name": name}
+    _, _, err := light.Bridge.PutContext(ctx, uri, body)
+    return err
+}
+
+// Light.DeleteContext is the context-aware counterpart to Light.Delete.
+func (light *Light) DeleteContext(ctx context.Context) error {
+    uri := fmt.Sprintf("/api/%s/lights/%d", light.Bridge.Username, light.Index)
+    return light.Bridge.DeleteContext(ctx, uri)
+}
+
+// Light.ColorLoopContext is the context-aware counterpart to
+// Light.ColorLoop.
+func (light *Light) ColorLoopContext(ctx context.Context, activate bool) error {
+    var state = "none"
+    if activate {
+        state = "colorloop"
+    }
+    return light.SetStateContext(ctx, LightState{On: true, Effect: state})
+}
+
+// Light.BlinkContext is the context-aware counterpart to Light.Blink. It
+// checks ctx.Done() between each toggle so a cancellation doesn't have to
+// wait for the full seconds*2 loop, and makes a best-effort attempt to
+// restore the light's original on/brightness state even when cancelled,
+// using a short-lived detached context so the restore isn't itself cut off.
+func (light *Light) BlinkContext(ctx context.Context, seconds int) error {
+    originalPosition := light.State.On
+    originalBrightness := light.State.Bri
+    blinkMax := LightState{On: true, Bri: uint8(200)}
+    blinkMin := LightState{On: true, Bri: uint8(50)}
+
+    restore := func() error {
+        if light.State.Bri != originalBrightness || light.State.On != originalPosition {
+            restoreCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+            defer cancel()
+            return light.SetStateContext(restoreCtx, LightState{On: originalPosition, Bri: uint8(originalBrightness)})
+        }
+        return nil
+    }
+
+    err := light.SetStateContext(ctx, blinkMax)
+    if err != nil {
+        return err
+    }
+
+    for i := 0; i <= seconds*2; i++ {
+        select {
+        case <-ctx.Done():
+            restore()
+            return ctx.Err()
+        default:
+        }
+
+        if i%2 == 0 {
+            err = light.SetStateContext(ctx, blinkMax)
+        } else {
+            err = light.SetStateContext(ctx, blinkMin)
+        }
+        if err != nil {
+            restore()
+            return err
+        }
+        time.Sleep(time.Second / 2)
+    }
+
+    restore()
+    return nil
+}
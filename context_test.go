@@ -0,0 +1,86 @@
+/*
+* context_test.go
+* GoHue library for Philips Hue
+* Copyright (C) 2016 Collin Guarino (Collinux) collin.guarino@gmail.com
+* License: GPL version 2 or higher http://www.gnu.org/licenses/gpl.html
+*/
+
+package hue
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "sync"
+    "testing"
+    "time"
+)
+
+func newBlinkTestLight(t *testing.T, originalOn bool, originalBri uint8) (*Light, *[]LightState) {
+    t.Helper()
+
+    var mutex sync.Mutex
+    var puts []LightState
+    last := LightState{On: originalOn, Bri: originalBri}
+
+    mux := http.NewServeMux()
+    mux.HandleFunc("/api/testuser/lights/0/state", func(w http.ResponseWriter, r *http.Request) {
+        var state LightState
+        json.NewDecoder(r.Body).Decode(&state)
+
+        mutex.Lock()
+        last = state
+        puts = append(puts, state)
+        mutex.Unlock()
+
+        w.Write([]byte(`[{"success":{}}]`))
+    })
+    mux.HandleFunc("/api/testuser/lights/0", func(w http.ResponseWriter, r *http.Request) {
+        mutex.Lock()
+        state := last
+        mutex.Unlock()
+
+        var light Light
+        light.State.On = state.On
+        light.State.Bri = int(state.Bri)
+        light.State.Reachable = true
+        json.NewEncoder(w).Encode(light)
+    })
+
+    bridge, _ := newTestBridge(t, mux)
+    light := &Light{Index: 0, Bridge: bridge}
+    light.State.On = originalOn
+    light.State.Bri = int(originalBri)
+
+    return light, &puts
+}
+
+func TestBlinkContextCancelReturnsEarlyAndRestores(t *testing.T) {
+    const originalBri = 10
+    light, puts := newBlinkTestLight(t, false, originalBri)
+
+    ctx, cancel := context.WithCancel(context.Background())
+    time.AfterFunc(150*time.Millisecond, cancel)
+
+    start := time.Now()
+    err := light.BlinkContext(ctx, 10)
+    elapsed := time.Since(start)
+
+    if err != context.Canceled {
+        t.Fatalf("BlinkContext error = %v, want context.Canceled", err)
+    }
+    // 10 seconds of blinking sleeps in half-second steps; cancelling at
+    // 150ms must return almost immediately rather than waiting out the
+    // full 10*2 loop.
+    if elapsed > 2*time.Second {
+        t.Errorf("BlinkContext took %v to return after cancellation, want well under the 20s blink loop", elapsed)
+    }
+
+    if len(*puts) == 0 {
+        t.Fatalf("expected at least one PUT, got none")
+    }
+    last := (*puts)[len(*puts)-1]
+    if last.On != false || last.Bri != originalBri {
+        t.Errorf("final PUT = %+v, want a restore to On=false Bri=%d", last, originalBri)
+    }
+}
@@ -0,0 +1,213 @@
+/*
+* huecolor.go
+* GoHue library for Philips Hue
+* Copyright (C) 2016 Collin Guarino (Collinux) collin.guarino@gmail.com
+* License: GPL version 2 or higher http://www.gnu.org/licenses/gpl.html
+*/
+// Package huecolor converts standard sRGB and hex color values into the
+// CIE 1931 (x, y) coordinates used by the Hue bridge, clamping the result
+// to the color gamut supported by the target bulb model.
+// See http://www.developers.meethue.com/documentation/color-conversions-rgb-xy
+package huecolor
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+// Point is an (x, y) coordinate in the CIE 1931 color space.
+type Point struct {
+    X float32
+    Y float32
+}
+
+// Gamut is the triangle of (x, y) points a bulb is able to reproduce.
+// Colors requested outside of the triangle are clamped to the nearest edge.
+type Gamut struct {
+    Red   Point
+    Green Point
+    Blue  Point
+}
+
+// Gamuts published by Philips for their light models.
+// http://www.developers.meethue.com/documentation/supported-lights
+var (
+    GamutA = Gamut{
+        Red:   Point{0.704, 0.296},
+        Green: Point{0.2151, 0.7106},
+        Blue:  Point{0.138, 0.080},
+    }
+    GamutB = Gamut{
+        Red:   Point{0.675, 0.322},
+        Green: Point{0.409, 0.518},
+        Blue:  Point{0.167, 0.040},
+    }
+    GamutC = Gamut{
+        Red:   Point{0.692, 0.308},
+        Green: Point{0.17, 0.7},
+        Blue:  Point{0.153, 0.048},
+    }
+)
+
+// modelGamuts maps a bulb's ModelID to the gamut it was shipped with.
+// Models not present here fall back to GamutC, the gamut used by the
+// current generation of Hue bulbs.
+var modelGamuts = map[string]Gamut{
+    // Gamut A
+    "LLC001": GamutA,
+    "LLC005": GamutA,
+    "LLC006": GamutA,
+    "LLC007": GamutA,
+    "LLC010": GamutA,
+    "LLC011": GamutA,
+    "LLC012": GamutA,
+    "LLC013": GamutA,
+    "LLC014": GamutA,
+    "LST001": GamutA,
+    // Gamut B
+    "LCT001": GamutB,
+    "LCT002": GamutB,
+    "LCT003": GamutB,
+    "LCT007": GamutB,
+    "LLM001": GamutB,
+    // Gamut C
+    "LCT010": GamutC,
+    "LCT011": GamutC,
+    "LCT012": GamutC,
+    "LCT014": GamutC,
+    "LCT015": GamutC,
+    "LCT016": GamutC,
+    "LLC020": GamutC,
+    "LST002": GamutC,
+}
+
+// GamutForModel returns the color gamut for the given Hue ModelID,
+// defaulting to GamutC when the model is unknown.
+func GamutForModel(modelID string) Gamut {
+    if gamut, ok := modelGamuts[modelID]; ok {
+        return gamut
+    }
+    return GamutC
+}
+
+// RGBToXY converts an 8-bit sRGB color into the CIE (x, y) point and
+// brightness (1-254) the bridge expects, clamped to gamut.
+func RGBToXY(r, g, b uint8, gamut Gamut) (xy [2]float32, bri uint8) {
+    red := srgbToLinear(float64(r) / 255)
+    green := srgbToLinear(float64(g) / 255)
+    blue := srgbToLinear(float64(b) / 255)
+
+    // Wide RGB D65 conversion matrix.
+    X := red*0.649926 + green*0.103455 + blue*0.197109
+    Y := red*0.234327 + green*0.743075 + blue*0.022598
+    Z := red*0.0000000 + green*0.053077 + blue*1.035763
+
+    sum := X + Y + Z
+    var point Point
+    if sum == 0 {
+        point = Point{0, 0}
+    } else {
+        point = Point{X: float32(X / sum), Y: float32(Y / sum)}
+    }
+    point = gamut.clamp(point)
+
+    brightness := Y * 254
+    if brightness > 254 {
+        brightness = 254
+    } else if brightness < 1 {
+        brightness = 1
+    }
+
+    return [2]float32{point.X, point.Y}, uint8(brightness)
+}
+
+// HexToXY converts a "#rrggbb" or "rrggbb" hex string into the CIE (x, y)
+// point and brightness the bridge expects, clamped to gamut.
+func HexToXY(hex string, gamut Gamut) (xy [2]float32, bri uint8, err error) {
+    hex = strings.TrimPrefix(hex, "#")
+    if len(hex) != 6 {
+        return [2]float32{}, 0, fmt.Errorf("huecolor: invalid hex color %q", hex)
+    }
+    raw, err := strconv.ParseUint(hex, 16, 32)
+    if err != nil {
+        return [2]float32{}, 0, fmt.Errorf("huecolor: invalid hex color %q: %v", hex, err)
+    }
+    r := uint8(raw >> 16)
+    g := uint8(raw >> 8)
+    b := uint8(raw)
+    xy, bri = RGBToXY(r, g, b, gamut)
+    return xy, bri, nil
+}
+
+// srgbToLinear applies inverse sRGB companding (gamma ~2.4 with the
+// standard linear toe) to a single 0-1 channel value.
+func srgbToLinear(c float64) float64 {
+    if c > 0.04045 {
+        return math.Pow((c+0.055)/1.055, 2.4)
+    }
+    return c / 12.92
+}
+
+// clamp projects p onto the nearest edge of the gamut triangle when it
+// falls outside of it, and returns p unchanged otherwise.
+func (gamut Gamut) clamp(p Point) Point {
+    if pointInTriangle(p, gamut.Red, gamut.Green, gamut.Blue) {
+        return p
+    }
+
+    redGreen := closestPointOnLine(gamut.Red, gamut.Green, p)
+    greenBlue := closestPointOnLine(gamut.Green, gamut.Blue, p)
+    blueRed := closestPointOnLine(gamut.Blue, gamut.Red, p)
+
+    dRedGreen := distance(p, redGreen)
+    dGreenBlue := distance(p, greenBlue)
+    dBlueRed := distance(p, blueRed)
+
+    closest := redGreen
+    min := dRedGreen
+    if dGreenBlue < min {
+        closest = greenBlue
+        min = dGreenBlue
+    }
+    if dBlueRed < min {
+        closest = blueRed
+    }
+    return closest
+}
+
+func closestPointOnLine(a, b, p Point) Point {
+    ap := Point{p.X - a.X, p.Y - a.Y}
+    ab := Point{b.X - a.X, b.Y - a.Y}
+
+    t := (ap.X*ab.X + ap.Y*ab.Y) / (ab.X*ab.X + ab.Y*ab.Y)
+    if t < 0 {
+        t = 0
+    } else if t > 1 {
+        t = 1
+    }
+    return Point{a.X + ab.X*t, a.Y + ab.Y*t}
+}
+
+func distance(a, b Point) float32 {
+    dx := float64(a.X - b.X)
+    dy := float64(a.Y - b.Y)
+    return float32(math.Sqrt(dx*dx + dy*dy))
+}
+
+// sign is used by pointInTriangle to determine which side of a line p falls on.
+func sign(p1, p2, p3 Point) float32 {
+    return (p1.X-p3.X)*(p2.Y-p3.Y) - (p2.X-p3.X)*(p1.Y-p3.Y)
+}
+
+func pointInTriangle(p, a, b, c Point) bool {
+    d1 := sign(p, a, b)
+    d2 := sign(p, b, c)
+    d3 := sign(p, c, a)
+
+    hasNeg := d1 < 0 || d2 < 0 || d3 < 0
+    hasPos := d1 > 0 || d2 > 0 || d3 > 0
+
+    return !(hasNeg && hasPos)
+}
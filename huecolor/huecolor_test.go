@@ -0,0 +1,89 @@
+/*
+* huecolor_test.go
+* GoHue library for Philips Hue
+* Copyright (C) 2016 Collin Guarino (Collinux) collin.guarino@gmail.com
+* License: GPL version 2 or higher http://www.gnu.org/licenses/gpl.html
+*/
+
+package huecolor
+
+import "testing"
+
+func TestRGBToXYBlackAndWhite(t *testing.T) {
+    cases := []struct {
+        name    string
+        r, g, b uint8
+        wantBri uint8
+    }{
+        {"black", 0, 0, 0, 1},
+        {"white", 255, 255, 255, 254},
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            _, bri := RGBToXY(c.r, c.g, c.b, GamutC)
+            if bri != c.wantBri {
+                t.Errorf("RGBToXY(%d,%d,%d) bri = %d, want %d", c.r, c.g, c.b, bri, c.wantBri)
+            }
+        })
+    }
+}
+
+func TestRGBToXYClampsToGamut(t *testing.T) {
+    // Pure blue is outside GamutA's triangle, so the result must land on
+    // (or inside) the triangle's boundary rather than at the raw,
+    // unclamped CIE point. Clamped points sit on an edge, so allow a small
+    // floating-point tolerance rather than requiring strict containment.
+    const epsilon = 1e-4
+    xy, _ := RGBToXY(0, 0, 255, GamutA)
+    p := Point{X: xy[0], Y: xy[1]}
+
+    onEdge := distance(p, closestPointOnLine(GamutA.Red, GamutA.Green, p)) <= epsilon ||
+        distance(p, closestPointOnLine(GamutA.Green, GamutA.Blue, p)) <= epsilon ||
+        distance(p, closestPointOnLine(GamutA.Blue, GamutA.Red, p)) <= epsilon
+
+    if !pointInTriangle(p, GamutA.Red, GamutA.Green, GamutA.Blue) && !onEdge {
+        t.Errorf("RGBToXY(0,0,255, GamutA) = %v, want a point inside or on the edge of GamutA", p)
+    }
+}
+
+func TestHexToXY(t *testing.T) {
+    withHash, _, err := HexToXY("#ff0000", GamutC)
+    if err != nil {
+        t.Fatalf("HexToXY(#ff0000) error: %v", err)
+    }
+    withoutHash, _, err := HexToXY("ff0000", GamutC)
+    if err != nil {
+        t.Fatalf("HexToXY(ff0000) error: %v", err)
+    }
+    if withHash != withoutHash {
+        t.Errorf("HexToXY with and without leading # disagree: %v vs %v", withHash, withoutHash)
+    }
+
+    wantXY, wantBri := RGBToXY(0xff, 0x00, 0x00, GamutC)
+    if withHash != wantXY {
+        t.Errorf("HexToXY(#ff0000) = %v, want %v", withHash, wantXY)
+    }
+    _, bri, _ := HexToXY("#ff0000", GamutC)
+    if bri != wantBri {
+        t.Errorf("HexToXY(#ff0000) bri = %d, want %d", bri, wantBri)
+    }
+}
+
+func TestHexToXYInvalid(t *testing.T) {
+    cases := []string{"", "fff", "gggggg", "#12345"}
+    for _, hex := range cases {
+        if _, _, err := HexToXY(hex, GamutC); err == nil {
+            t.Errorf("HexToXY(%q) expected an error, got nil", hex)
+        }
+    }
+}
+
+func TestGamutForModelDefaultsToC(t *testing.T) {
+    if got := GamutForModel("unknown-model"); got != GamutC {
+        t.Errorf("GamutForModel(unknown) = %v, want GamutC", got)
+    }
+    if got := GamutForModel("LCT001"); got != GamutB {
+        t.Errorf("GamutForModel(LCT001) = %v, want GamutB", got)
+    }
+}
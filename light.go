@@ -11,6 +11,8 @@ package hue
 import (
     "fmt"
     "time"
+
+    "github.com/collinux/gohue/huecolor"
 )
 
 // Light struct defines attributes of a light.
@@ -172,22 +174,57 @@ func (light *Light) SetColor(color *[2]float32) error {
     return nil
 }
 
+// Light.SetRGB converts an 8-bit sRGB color into the CIE xy coordinate
+// the bridge expects, clamped to the gamut of the bulb's `ModelID`,
+// and applies it to the light.
+func (light *Light) SetRGB(r, g, b uint8) error {
+    gamut := huecolor.GamutForModel(light.ModelID)
+    xy, bri := huecolor.RGBToXY(r, g, b, gamut)
+    return light.SetState(LightState{On: true, XY: &xy, Bri: bri})
+}
+
+// Light.SetHex converts a "#rrggbb" or "rrggbb" hex color into the CIE xy
+// coordinate the bridge expects, clamped to the gamut of the bulb's
+// `ModelID`, and applies it to the light.
+func (light *Light) SetHex(hex string) error {
+    gamut := huecolor.GamutForModel(light.ModelID)
+    xy, bri, err := huecolor.HexToXY(hex, gamut)
+    if err != nil {
+        return err
+    }
+    return light.SetState(LightState{On: true, XY: &xy, Bri: bri})
+}
+
 // Light.SetState modifyies light attributes. See `LightState` struct for attributes.
 // Brightness must be between 1 and 254 (inclusive)
 // Hue must be between 0 and 65535 (inclusive)
 // Sat must be between 0 and 254 (inclusive)
 // See http://www.developers.meethue.com/documentation/lights-api for more info
 func (light *Light) SetState(newState LightState) error {
+    cache := cacheFor(light.Bridge)
+    if cache.checkCache(light.Index, newState) {
+        return nil
+    }
+
     uri := fmt.Sprintf("/api/%s/lights/%d/state", light.Bridge.Username, light.Index)
-    _, _, err := light.Bridge.Put(uri, newState)
+
+    first, second := applyQuirks(*light, newState)
+    _, _, err := light.Bridge.Put(uri, first)
     if err != nil {
         return err
     }
+    if second != nil {
+        _, _, err = light.Bridge.Put(uri, *second)
+        if err != nil {
+            return err
+        }
+    }
 
     // Get the new light state and update the current Light struct
     *light, err = light.Bridge.GetLightByIndex(light.Index)
     if err != nil {
         return err
     }
+    cache.record(light.Index, newState, light.State.Reachable)
     return nil
 }
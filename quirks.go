@@ -0,0 +1,119 @@
+/*
+* quirks.go
+* GoHue library for Philips Hue
+* Copyright (C) 2016 Collin Guarino (Collinux) collin.guarino@gmail.com
+* License: GPL version 2 or higher http://www.gnu.org/licenses/gpl.html
+*/
+// http://www.developers.meethue.com/documentation/lights-api
+
+package hue
+
+import "strconv"
+
+// LightQuirks describes how a non-Philips bulb deviates from the standard
+// Hue `state` PUT behavior so `Light.SetState` can work around it.
+type LightQuirks struct {
+    // ForceXYOnModeChange sends an XY write whenever `On` transitions from
+    // false to true, since some Zigbee bulbs ignore a bare `{"on":true}`
+    // and stay in their last color mode instead of switching on.
+    ForceXYOnModeChange bool
+
+    // SplitOnAndColor sends `on` in its own PUT before a second PUT carrying
+    // color/brightness fields, rather than combining them in one request.
+    SplitOnAndColor bool
+
+    // MinTransitionTime is the smallest `transitiontime` (in deciseconds)
+    // the bulb will reliably honor; shorter requests are raised to this floor.
+    MinTransitionTime int
+}
+
+// quirkMatcher pairs a predicate with the quirks to apply when it matches.
+type quirkMatcher struct {
+    match  func(Light) bool
+    quirks LightQuirks
+}
+
+// quirksRegistry holds all registered matchers, consulted in reverse
+// registration order so a caller can register a more specific matcher
+// after the defaults (registered in `init()`) and have it take precedence.
+var quirksRegistry []quirkMatcher
+
+// RegisterQuirks adds a matcher to the quirks registry. `match` is
+// evaluated against a light's `ManufacturerName` and `ModelID`; the most
+// recently registered matcher that returns true has its `LightQuirks`
+// applied by `Light.SetState`, so a caller can override or narrow the
+// shipped defaults by registering afterward.
+func RegisterQuirks(match func(Light) bool, quirks LightQuirks) {
+    quirksRegistry = append(quirksRegistry, quirkMatcher{match: match, quirks: quirks})
+}
+
+// quirksFor returns the registered LightQuirks for light, and false if no
+// matcher applies.
+func quirksFor(light Light) (LightQuirks, bool) {
+    for i := len(quirksRegistry) - 1; i >= 0; i-- {
+        matcher := quirksRegistry[i]
+        if matcher.match(light) {
+            return matcher.quirks, true
+        }
+    }
+    return LightQuirks{}, false
+}
+
+// tradfriModelIDs lists the IKEA TradFri bulb model IDs known to need an
+// XY write to reliably switch modes.
+var tradfriModelIDs = map[string]bool{
+    "TRADFRI bulb E27 WS opal 980lm":    true,
+    "TRADFRI bulb E27 WS clear 950lm":   true,
+    "TRADFRI bulb E27 CWS opal 600lm":   true,
+    "TRADFRI bulb E14 WS opal 400lm":    true,
+    "TRADFRI bulb GU10 WS 400lm":        true,
+}
+
+func init() {
+    RegisterQuirks(
+        func(light Light) bool {
+            return light.ManufacturerName == "IKEA of Sweden" && tradfriModelIDs[light.ModelID]
+        },
+        LightQuirks{
+            ForceXYOnModeChange: true,
+            SplitOnAndColor:     true,
+            MinTransitionTime:   4,
+        },
+    )
+}
+
+// applyQuirks rewrites newState per the quirks registered for light, and
+// returns any additional state that should be PUT in a follow-up request
+// (non-nil only when the matched quirks set SplitOnAndColor).
+func applyQuirks(light Light, newState LightState) (first LightState, second *LightState) {
+    quirks, ok := quirksFor(light)
+    if !ok {
+        return newState, nil
+    }
+
+    if quirks.ForceXYOnModeChange && newState.On && !light.State.On && newState.XY == nil {
+        newState.XY = &light.State.XY
+    }
+
+    if quirks.MinTransitionTime > 0 {
+        current, err := strconv.Atoi(newState.TransitionTime)
+        if err != nil || current < quirks.MinTransitionTime {
+            newState.TransitionTime = strconv.Itoa(quirks.MinTransitionTime)
+        }
+    }
+
+    if quirks.SplitOnAndColor && hasColorFields(newState) {
+        onOnly := LightState{On: newState.On}
+        second := newState
+        return onOnly, &second
+    }
+
+    return newState, nil
+}
+
+// hasColorFields reports whether state carries a color field (xy, hue, sat,
+// ct or effect) that would need to be split into a follow-up PUT under
+// SplitOnAndColor. Brightness and transition time alone do not count.
+func hasColorFields(state LightState) bool {
+    return state.XY != nil || state.Hue != 0 || state.Sat != 0 || state.CT != 0 || state.Effect != ""
+}
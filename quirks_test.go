@@ -0,0 +1,113 @@
+/*
+* quirks_test.go
+* GoHue library for Philips Hue
+* Copyright (C) 2016 Collin Guarino (Collinux) collin.guarino@gmail.com
+* License: GPL version 2 or higher http://www.gnu.org/licenses/gpl.html
+*/
+
+package hue
+
+import "testing"
+
+func tradfriLight() Light {
+    light := Light{
+        ManufacturerName: "IKEA of Sweden",
+        ModelID:          "TRADFRI bulb E27 WS opal 980lm",
+    }
+    light.State.On = false
+    light.State.XY = [2]float32{0.4, 0.4}
+    return light
+}
+
+func TestApplyQuirksSplitPreservesOn(t *testing.T) {
+    light := tradfriLight()
+    xy := [2]float32{0.5, 0.5}
+    newState := LightState{On: true, XY: &xy, Bri: 200}
+
+    first, second := applyQuirks(light, newState)
+
+    if !first.On {
+        t.Errorf("first PUT On = false, want true")
+    }
+    if second == nil {
+        t.Fatalf("expected a second PUT for a TradFri color change, got nil")
+    }
+    if !second.On {
+        t.Errorf("second PUT On = false, want true (must not turn the bulb back off)")
+    }
+    if *second.XY != xy {
+        t.Errorf("second PUT XY = %v, want %v", *second.XY, xy)
+    }
+    if second.Bri != 200 {
+        t.Errorf("second PUT Bri = %d, want 200", second.Bri)
+    }
+}
+
+func TestApplyQuirksForcesXYOnModeChange(t *testing.T) {
+    light := tradfriLight()
+    newState := LightState{On: true}
+
+    // Forcing an XY write gives newState a color field, which in turn
+    // triggers this light's SplitOnAndColor quirk, so the forced XY lands
+    // in the second (color) PUT alongside the bare `on` first PUT.
+    first, second := applyQuirks(light, newState)
+    if first.XY != nil {
+        t.Errorf("first PUT XY = %v, want nil (on/off only)", first.XY)
+    }
+    if second == nil || second.XY == nil {
+        t.Fatalf("expected ForceXYOnModeChange to populate XY on the second PUT, got %v", second)
+    }
+    if *second.XY != light.State.XY {
+        t.Errorf("forced XY = %v, want light's current XY %v", *second.XY, light.State.XY)
+    }
+}
+
+func TestApplyQuirksSetsMinTransitionTime(t *testing.T) {
+    light := tradfriLight()
+    xy := [2]float32{0.5, 0.5}
+    newState := LightState{On: true, XY: &xy, Bri: 100}
+
+    _, second := applyQuirks(light, newState)
+    if second == nil {
+        t.Fatalf("expected a second (color) PUT, got nil")
+    }
+    if second.TransitionTime != "4" {
+        t.Errorf("TransitionTime = %q, want %q", second.TransitionTime, "4")
+    }
+}
+
+func TestApplyQuirksNoMatchPassesThrough(t *testing.T) {
+    light := Light{ManufacturerName: "Philips", ModelID: "LCT010"}
+    newState := LightState{On: true, Bri: 100}
+
+    first, second := applyQuirks(light, newState)
+    if second != nil {
+        t.Errorf("expected no split for a non-quirky light, got %v", second)
+    }
+    if first != newState {
+        t.Errorf("expected state to pass through unchanged, got %v", first)
+    }
+}
+
+func TestRegisterQuirksOverridesDefault(t *testing.T) {
+    reset := len(quirksRegistry)
+    t.Cleanup(func() { quirksRegistry = quirksRegistry[:reset] })
+
+    RegisterQuirks(
+        func(light Light) bool {
+            return light.ManufacturerName == "IKEA of Sweden"
+        },
+        LightQuirks{MinTransitionTime: 10},
+    )
+
+    quirks, ok := quirksFor(tradfriLight())
+    if !ok {
+        t.Fatalf("expected a match for the overriding matcher")
+    }
+    if quirks.SplitOnAndColor {
+        t.Errorf("expected the later registration to override the shipped default, got %+v", quirks)
+    }
+    if quirks.MinTransitionTime != 10 {
+        t.Errorf("MinTransitionTime = %d, want 10", quirks.MinTransitionTime)
+    }
+}